@@ -0,0 +1,29 @@
+package tnef
+
+import "time"
+
+// Named MAPI properties used by appointment/meeting items (MS-OXOCAL). TNEF
+// doesn't carry the GUID/name mapping a fully general named-property
+// resolver would need, so these use the property IDs Outlook and Exchange
+// conventionally assign when they convert PSETID_Appointment/PSETID_Meeting
+// named properties for TNEF transport.
+const (
+	MAPILocation          = uint32(ptString8)<<16 | 0x8208 // PidLidLocation
+	MAPIRecurrencePattern = uint32(ptBinary)<<16 | 0x8216  // PidLidAppointmentRecur
+	MAPIGlobalObjectID    = uint32(ptBinary)<<16 | 0x0023  // PidLidGlobalObjectId
+	MAPIResponseStatus    = uint32(ptLong)<<16 | 0x8218    // PidLidResponseStatus
+)
+
+// Appointment holds the meeting/task attributes TNEF carries for Outlook
+// calendar items, assembled from the top-level date/owner attributes plus
+// the calendar-specific named properties inside ATTMAPIPROPS.
+type Appointment struct {
+	Start             time.Time
+	End               time.Time
+	Location          string
+	UID               string
+	ResponseStatus    uint32
+	ResponseRequested bool
+	OwnerApptID       []byte
+	RecurrencePattern []byte
+}
@@ -0,0 +1,260 @@
+package tnef
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// defaultMaxObjectSize bounds how large a single TNEF object's body is
+// allowed to be when DecoderOptions.MaxObjectSize is left at zero, so a
+// malformed or fuzzed length field can't make Decode try to allocate an
+// absurd amount of memory.
+const defaultMaxObjectSize = 512 << 20 // 512 MiB
+
+// DecoderOptions controls how strictly a Decoder validates the stream it is
+// reading.
+type DecoderOptions struct {
+	// IgnoreChecksums skips verifying each object's trailing 16-bit
+	// checksum. Off by default: operators decoding untrusted mail (spam
+	// or quarantine pipelines) want malformed objects rejected rather
+	// than silently accepted.
+	IgnoreChecksums bool
+
+	// MaxObjectSize caps the body length of a single TNEF object. Zero
+	// means defaultMaxObjectSize; a negative value disables the check.
+	MaxObjectSize int
+}
+
+// Decoder reads a TNEF stream incrementally from an io.Reader, so callers
+// processing very large winmail.dat blobs don't have to read the whole
+// thing into memory up front.
+type Decoder struct {
+	r      *bufio.Reader
+	key    uint16
+	header bool
+
+	// Options controls checksum validation and object size limits. It
+	// may be set any time before the first call to Next or Decode.
+	Options DecoderOptions
+
+	// AttachmentWriter, when set, is called as soon as a new attachment
+	// starts (ATTATTACHRENDDATA). Its returned io.Writer receives that
+	// attachment's ATTATTACHDATA bytes directly, instead of Decode
+	// buffering them into Attachment.Data, so the caller can stream each
+	// attachment straight to disk and let the backing memory go.
+	AttachmentWriter func(*Attachment) (io.Writer, error)
+
+	attachment    *Attachment
+	attachmentOut io.Writer
+
+	// embedDepth and embedBudget bound recursive embedded-TNEF decoding
+	// (an attachment whose MAPIAttachDataObj is itself a TNEF stream,
+	// possibly containing another, and so on). embedBudget is nil until
+	// Decode allocates one, and is shared across an entire decode tree so
+	// nesting depth and cumulative bytes decoded are tracked across every
+	// level, not just the current one.
+	embedDepth  int
+	embedBudget *embedBudget
+}
+
+// These errors are returned by decodeEmbedded when recursive
+// embedded-attachment decoding would exceed maxEmbedDepth or
+// maxEmbedTotalSize; Attachment.addAttr fails closed on them instead of
+// treating them like an ordinary malformed attribute.
+var (
+	errEmbedTooDeep = errors.New("tnef: embedded attachment nesting exceeds limit")
+	errEmbedTooBig  = errors.New("tnef: embedded attachment data exceeds cumulative size limit")
+)
+
+const (
+	// maxEmbedDepth caps how many attachment-in-attachment levels Decode
+	// will unwrap before failing closed, so a deeply nested chain can't
+	// make memory cost scale with depth.
+	maxEmbedDepth = 10
+
+	// maxEmbedTotalSize caps the combined bytes of every embedded TNEF
+	// stream decoded across a single top-level Decode call.
+	maxEmbedTotalSize = 64 << 20 // 64 MiB
+)
+
+// embedBudget tracks the cumulative bytes spent decoding embedded
+// attachments across an entire decode tree.
+type embedBudget struct {
+	totalSize int64
+}
+
+// decodeEmbedded decodes a nested TNEF stream found inside an attachment's
+// MAPIAttachDataObj, enforcing depth and cumulative size limits against
+// runaway attachment-in-attachment nesting. It inherits opts from the
+// Decoder doing the embedding, rather than the package-level Decode's
+// zero-value defaults, so an operator who tightens e.g. MaxObjectSize to
+// survive hostile input isn't silently overridden back to the permissive
+// default as soon as an attachment nests another TNEF stream. A malformed
+// nested stream is not itself a resource-exhaustion risk, so (unlike at the
+// top level, where Decode returns the error) it is reported back as a nil
+// result rather than failing the whole decode.
+func decodeEmbedded(data []byte, opts DecoderOptions, budget *embedBudget, depth int) (*Data, error) {
+	if depth >= maxEmbedDepth {
+		return nil, errEmbedTooDeep
+	}
+	budget.totalSize += int64(len(data))
+	if budget.totalSize > maxEmbedTotalSize {
+		return nil, errEmbedTooBig
+	}
+
+	d := NewDecoder(bytes.NewReader(data))
+	d.Options = opts
+	d.embedDepth = depth + 1
+	d.embedBudget = budget
+
+	tnef, err := d.Decode()
+	if err == errEmbedTooDeep || err == errEmbedTooBig {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil
+	}
+	return tnef, nil
+}
+
+// NewDecoder returns a Decoder that reads a TNEF stream from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+func (d *Decoder) maxObjectSize() int {
+	switch {
+	case d.Options.MaxObjectSize > 0:
+		return d.Options.MaxObjectSize
+	case d.Options.MaxObjectSize < 0:
+		return -1
+	default:
+		return defaultMaxObjectSize
+	}
+}
+
+// readHeader reads and validates the fixed 6-byte TNEF header (signature
+// followed by a 16-bit key), once per Decoder.
+func (d *Decoder) readHeader() error {
+	var header [6]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return ErrNoMarker
+	}
+
+	if binary.LittleEndian.Uint32(header[:4]) != tnefSignature {
+		return ErrNoMarker
+	}
+	d.key = binary.LittleEndian.Uint16(header[4:])
+	d.header = true
+	return nil
+}
+
+// Next reads and returns the next TNEF object from the stream, or io.EOF
+// once the stream is exhausted.
+func (d *Decoder) Next() (*TNEFObject, error) {
+	if !d.header {
+		if err := d.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	var prefix [9]byte
+	if _, err := io.ReadFull(d.r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.New("tnef: truncated object prefix")
+	}
+
+	length := binary.LittleEndian.Uint32(prefix[5:9])
+	if max := d.maxObjectSize(); max >= 0 && length > uint32(max) {
+		return nil, errors.New("tnef: object length exceeds MaxObjectSize")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(d.r, body); err != nil {
+		return nil, errors.New("tnef: truncated object body")
+	}
+
+	var checksum [2]byte
+	if _, err := io.ReadFull(d.r, checksum[:]); err != nil {
+		return nil, errors.New("tnef: truncated object checksum")
+	}
+
+	if !d.Options.IgnoreChecksums {
+		var sum uint16
+		for _, b := range body {
+			sum += uint16(b)
+		}
+		if sum != binary.LittleEndian.Uint16(checksum[:]) {
+			return nil, errors.New("tnef: object checksum mismatch")
+		}
+	}
+
+	return &TNEFObject{
+		Level:  uint8(prefix[0]),
+		Name:   binary.LittleEndian.Uint16(prefix[1:3]),
+		Type:   binary.BigEndian.Uint16(prefix[3:5]),
+		Data:   body,
+		Length: uint32(len(prefix)) + length + uint32(len(checksum)),
+	}, nil
+}
+
+// Decode reads the rest of the stream and assembles a Data, the streaming
+// equivalent of the package-level Decode function.
+func (d *Decoder) Decode() (*Data, error) {
+	tnef := &Data{Attachments: []*Attachment{}}
+	tnef.key = d.key
+
+	if d.embedBudget == nil {
+		d.embedBudget = &embedBudget{}
+	}
+
+	for {
+		obj, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tnef.key = d.key
+
+		switch {
+		case obj.Name == ATTATTACHRENDDATA:
+			d.attachment = &Attachment{}
+			tnef.Attachments = append(tnef.Attachments, d.attachment)
+
+			d.attachmentOut = nil
+			if d.AttachmentWriter != nil {
+				w, err := d.AttachmentWriter(d.attachment)
+				if err != nil {
+					return nil, err
+				}
+				d.attachmentOut = w
+			}
+		case obj.Level == lvlAttachment:
+			if d.attachment == nil {
+				continue
+			}
+			if obj.Name == ATTATTACHDATA && d.attachmentOut != nil {
+				if _, err := d.attachmentOut.Write(obj.Data); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := d.attachment.addAttr(obj, d.Options, d.embedBudget, d.embedDepth); err != nil {
+				return nil, err
+			}
+		default:
+			if err := tnef.addAttr(obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tnef, nil
+}
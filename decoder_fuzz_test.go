@@ -0,0 +1,90 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func seedTNEFObject(level uint8, name uint16, data []byte) []byte {
+	buf := make([]byte, 9+len(data)+2)
+	buf[0] = level
+	binary.LittleEndian.PutUint16(buf[1:], name)
+	binary.LittleEndian.PutUint32(buf[5:], uint32(len(data)))
+	copy(buf[9:], data)
+
+	var sum uint16
+	for _, b := range data {
+		sum += uint16(b)
+	}
+	binary.LittleEndian.PutUint16(buf[9+len(data):], sum)
+	return buf
+}
+
+func FuzzDecode(f *testing.F) {
+	var valid []byte
+	header := make([]byte, 6)
+	binary.LittleEndian.PutUint32(header, tnefSignature)
+	binary.LittleEndian.PutUint16(header[4:], 1)
+	valid = append(valid, header...)
+	valid = append(valid, seedTNEFObject(lvlMessage, ATTSUBJECT, []byte("hello\x00"))...)
+	valid = append(valid, seedTNEFObject(lvlAttachment, ATTATTACHDATA, []byte("data"))...)
+
+	f.Add(valid)
+	f.Add([]byte{})
+	f.Add(header)
+	f.Add(append(append([]byte{}, header...), 0x02))
+
+	// Seeds reaching the MAPI property stream paths (ATTMAPIPROPS,
+	// ATTRECIPTABLE) and the LZFu decompressor (PR_RTF_COMPRESSED), so
+	// fuzzing actually exercises the code that decodes attacker-controlled
+	// length/count fields nested inside an object's payload, not just the
+	// outer TNEF object length.
+	var withMapiProps []byte
+	withMapiProps = append(withMapiProps, header...)
+	withMapiProps = append(withMapiProps, seedTNEFObject(lvlMessage, ATTMAPIPROPS,
+		buildMapiString8Row(map[uint32]string{MAPISubject: "hello"}))...)
+	f.Add(withMapiProps)
+
+	var withRecipientTable []byte
+	withRecipientTable = append(withRecipientTable, header...)
+	withRecipientTable = append(withRecipientTable, seedTNEFObject(lvlMessage, ATTRECIPTABLE,
+		buildRecipientTable(1, buildMapiString8Row(map[uint32]string{
+			MAPIDisplayName:  "Alice",
+			MAPIEmailAddress: "alice@example.com",
+		})))...)
+	f.Add(withRecipientTable)
+
+	var withRTFCompressed []byte
+	withRTFCompressed = append(withRTFCompressed, header...)
+	withRTFCompressed = append(withRTFCompressed, seedTNEFObject(lvlMessage, ATTMAPIPROPS,
+		buildMapiString8Row(map[uint32]string{
+			MAPIRTFCompressed: string(buildCompressedRTF(rtfCompressed, 2, []byte{0x00, 'A', 'B'})),
+		}))...)
+	f.Add(withRTFCompressed)
+
+	// Regression seeds for the two prealloc allocation bombs: a bogus
+	// rowCount/rawSize paired with far too little payload to back it.
+	var bogusRowCount []byte
+	bogusRowCount = append(bogusRowCount, header...)
+	bogusRowCount = append(bogusRowCount, seedTNEFObject(lvlMessage, ATTRECIPTABLE,
+		buildRecipientTable(0xfffffff0))...)
+	f.Add(bogusRowCount)
+
+	var bogusRawSize []byte
+	bogusRawSize = append(bogusRawSize, header...)
+	bogusRawSize = append(bogusRawSize, seedTNEFObject(lvlMessage, ATTMAPIPROPS,
+		buildMapiString8Row(map[uint32]string{
+			MAPIRTFCompressed: string(buildCompressedRTF(rtfCompressed, 0xfffffff0, nil)),
+		}))...)
+	f.Add(bogusRawSize)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Decode must never panic, regardless of how malformed data is.
+		_, _ = Decode(data)
+
+		d := NewDecoder(bytes.NewReader(data))
+		d.Options.MaxObjectSize = 1 << 20
+		_, _ = d.Decode()
+	})
+}
@@ -0,0 +1,123 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildTNEFStream assembles a full TNEF blob: the fixed header followed by
+// the given pre-built objects (e.g. from seedTNEFObject).
+func buildTNEFStream(objects ...[]byte) []byte {
+	header := make([]byte, 6)
+	binary.LittleEndian.PutUint32(header, tnefSignature)
+	binary.LittleEndian.PutUint16(header[4:], 1)
+
+	buf := append([]byte{}, header...)
+	for _, obj := range objects {
+		buf = append(buf, obj...)
+	}
+	return buf
+}
+
+// wrapAsAttachment returns a TNEF stream with a single attachment whose
+// MAPIAttachDataObj embeds inner, so decoding it recurses into inner as a
+// nested TNEF stream.
+func wrapAsAttachment(inner []byte) []byte {
+	attachment := buildMapiString8Row(map[uint32]string{
+		MAPIAttachDataObj: string(inner),
+	})
+	return buildTNEFStream(
+		seedTNEFObject(lvlAttachment, ATTATTACHRENDDATA, nil),
+		seedTNEFObject(lvlAttachment, ATTATTACHMENT, attachment),
+	)
+}
+
+func TestDecodeEmbeddedDepthLimit(t *testing.T) {
+	leaf := buildTNEFStream(seedTNEFObject(lvlMessage, ATTSUBJECT, []byte("leaf\x00")))
+
+	t.Run("within the limit decodes fine", func(t *testing.T) {
+		stream := leaf
+		for i := 0; i < maxEmbedDepth-1; i++ {
+			stream = wrapAsAttachment(stream)
+		}
+
+		tnef, err := Decode(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := tnef
+		for i := 0; i < maxEmbedDepth-1; i++ {
+			if len(got.Attachments) != 1 || got.Attachments[0].Embedded == nil {
+				t.Fatalf("expected a decoded embedded attachment at level %d", i)
+			}
+			got = got.Attachments[0].Embedded
+		}
+		if got.Subject != "leaf" {
+			t.Fatalf("got subject %q, want %q", got.Subject, "leaf")
+		}
+	})
+
+	t.Run("exceeding the limit fails closed instead of recursing forever", func(t *testing.T) {
+		stream := leaf
+		for i := 0; i < maxEmbedDepth+5; i++ {
+			stream = wrapAsAttachment(stream)
+		}
+
+		if _, err := Decode(stream); err == nil {
+			t.Fatal("expected an error once embedded nesting exceeds maxEmbedDepth")
+		}
+	})
+}
+
+func TestDecodeEmbeddedSizeLimit(t *testing.T) {
+	// A single level of nesting whose embedded blob alone is bigger than
+	// maxEmbedTotalSize must fail closed well before maxEmbedDepth would
+	// ever kick in, confirming the cumulative-size accounting is enforced
+	// independently of the depth counter.
+	big := make([]byte, maxEmbedTotalSize+(1<<20))
+	leaf := buildTNEFStream(seedTNEFObject(lvlMessage, ATTBODY, big))
+	stream := wrapAsAttachment(leaf)
+
+	if _, err := Decode(stream); err == nil {
+		t.Fatal("expected an error once cumulative embedded size exceeds maxEmbedTotalSize")
+	}
+}
+
+func TestDecodeEmbeddedInheritsOptions(t *testing.T) {
+	// A leaf stream whose only object carries a deliberately corrupted
+	// checksum, nested one level deep.
+	leafObj := seedTNEFObject(lvlMessage, ATTSUBJECT, []byte("leaf\x00"))
+	leafObj[len(leafObj)-1] ^= 0xff // corrupt the trailing checksum byte
+	leaf := buildTNEFStream(leafObj)
+	stream := wrapAsAttachment(leaf)
+
+	// The package-level Decode (and a Decoder with zero-value Options)
+	// rejects the corrupted checksum, so the embedded stream fails to
+	// decode and Embedded is left nil — same as any other malformed
+	// nested attachment.
+	tnef, err := Decode(stream)
+	if err != nil {
+		t.Fatalf("Decode should succeed at the outer level: %v", err)
+	}
+	if len(tnef.Attachments) != 1 || tnef.Attachments[0].Embedded != nil {
+		t.Fatalf("expected Embedded to stay nil when the nested checksum is rejected, got %+v", tnef.Attachments[0])
+	}
+
+	// A Decoder that opts into IgnoreChecksums must have that same
+	// leniency carried into the nested embedded decode, not silently
+	// reset back to strict checking.
+	d := NewDecoder(bytes.NewReader(stream))
+	d.Options.IgnoreChecksums = true
+	tnef, err = d.Decode()
+	if err != nil {
+		t.Fatalf("Decode with IgnoreChecksums should succeed: %v", err)
+	}
+	if len(tnef.Attachments) != 1 || tnef.Attachments[0].Embedded == nil {
+		t.Fatal("expected the parent Decoder's IgnoreChecksums to apply to the embedded decode")
+	}
+	if got := tnef.Attachments[0].Embedded.Subject; got != "leaf" {
+		t.Fatalf("got embedded subject %q, want %q", got, "leaf")
+	}
+}
@@ -0,0 +1,23 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// imessageSig marks the start of an embedded IMessage/CFBF (compound
+// document, i.e. a .msg file) stored inside MAPIAttachDataObj.
+var imessageSig = []byte{0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}
+
+// detectEmbeddedSignature reports whether data looks like an embedded TNEF
+// stream or an embedded IMessage/CFBF compound document, as found inside
+// MAPIAttachDataObj when an attachment is itself a forwarded message.
+func detectEmbeddedSignature(data []byte) (isTNEF, isMessage bool) {
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == tnefSignature {
+		return true, false
+	}
+	if bytes.HasPrefix(data, imessageSig) {
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,251 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+const base64LineLength = 76
+
+// EML renders tnef as a standards-compliant RFC 5322 / MIME message: a
+// multipart/mixed envelope carrying a multipart/alternative body (whichever
+// of Body, BodyHTML and RTFBody were decoded) followed by every Attachment
+// as its own part.
+func (tnef *Data) EML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tnef.WriteEML(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteEML writes the message EML renders directly to w.
+func (tnef *Data) WriteEML(w io.Writer) error {
+	altBody, altBoundary, err := tnef.buildAlternativeBody()
+	if err != nil {
+		return err
+	}
+
+	mixed := multipart.NewWriter(w)
+	if err := tnef.writeHeaders(w, mixed.Boundary()); err != nil {
+		return err
+	}
+
+	if altBody != nil {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"multipart/alternative; boundary=" + altBoundary},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(altBody); err != nil {
+			return err
+		}
+	} else if len(tnef.Attachments) == 0 {
+		// RFC 2046 requires multipart bodies to carry at least one part;
+		// fall back to an empty text/plain part rather than emit a
+		// bodiless, attachment-less message.
+		if err := writeTextPart(mixed, "text/plain; charset=utf-8", nil); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range tnef.Attachments {
+		if err := writeAttachmentPart(mixed, a); err != nil {
+			return err
+		}
+	}
+
+	return mixed.Close()
+}
+
+// writeHeaders writes the RFC 5322 headers for the top-level message,
+// ahead of the first MIME boundary.
+func (tnef *Data) writeHeaders(w io.Writer, boundary string) error {
+	if id := sanitizeHeaderValue(messageID(tnef.MessageID)); id != "" {
+		if _, err := fmt.Fprintf(w, "Message-ID: %s\r\n", id); err != nil {
+			return err
+		}
+	}
+
+	headers := []struct{ name, value string }{
+		{"From", tnef.From},
+		{"To", joinRecipients(tnef.Recipients, 1)},
+		{"Cc", joinRecipients(tnef.Recipients, 2)},
+		{"Subject", tnef.Subject},
+	}
+
+	for _, h := range headers {
+		value := sanitizeHeaderValue(h.value)
+		if value == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\r\n", h.name, mime.QEncoding.Encode("utf-8", value)); err != nil {
+			return err
+		}
+	}
+	if !tnef.DateSent.IsZero() {
+		if _, err := fmt.Fprintf(w, "Date: %s\r\n", tnef.DateSent.Format("Mon, 02 Jan 2006 15:04:05 -0700")); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w,
+		"MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		boundary)
+	return err
+}
+
+// messageID wraps id in angle brackets if it isn't already, the form
+// RFC 5322 requires for Message-ID.
+func messageID(id string) string {
+	if id == "" || strings.HasPrefix(id, "<") {
+		return id
+	}
+	return "<" + id + ">"
+}
+
+// sanitizeHeaderValue strips CR and LF from a value pulled out of
+// attacker-controlled TNEF data before it's written into a header, so a
+// crafted MAPI string property can't inject extra headers or MIME parts.
+func sanitizeHeaderValue(s string) string {
+	r := strings.NewReplacer("\r", "", "\n", "")
+	return r.Replace(s)
+}
+
+// joinRecipients renders the display names/addresses of every Recipient of
+// the given RecipType (MAPI_TO=1, MAPI_CC=2, MAPI_BCC=3) as a comma
+// separated address list.
+func joinRecipients(recipients []Recipient, recipType uint32) string {
+	var addrs []string
+	for _, r := range recipients {
+		if r.RecipType != recipType {
+			continue
+		}
+		addr := r.EmailAddress
+		if addr == "" {
+			addr = r.DisplayName
+		}
+		if addr == "" {
+			continue
+		}
+		if r.DisplayName != "" && r.DisplayName != addr {
+			addrs = append(addrs, fmt.Sprintf("%s <%s>", r.DisplayName, addr))
+		} else {
+			addrs = append(addrs, addr)
+		}
+	}
+	return strings.Join(addrs, ", ")
+}
+
+// buildAlternativeBody renders Body, BodyHTML and RTFBody (whichever are
+// present) as a multipart/alternative body, returning nil if none were
+// decoded.
+func (tnef *Data) buildAlternativeBody() ([]byte, string, error) {
+	if len(tnef.Body) == 0 && len(tnef.BodyHTML) == 0 && len(tnef.RTFBody) == 0 {
+		return nil, "", nil
+	}
+
+	var buf bytes.Buffer
+	alt := multipart.NewWriter(&buf)
+
+	if len(tnef.Body) > 0 {
+		if err := writeTextPart(alt, "text/plain; charset=utf-8", tnef.Body); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(tnef.RTFBody) > 0 {
+		if err := writeBase64Part(alt, "application/rtf", nil, tnef.RTFBody); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(tnef.BodyHTML) > 0 {
+		if err := writeTextPart(alt, "text/html; charset=utf-8", tnef.BodyHTML); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := alt.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), alt.Boundary(), nil
+}
+
+func writeTextPart(mw *multipart.Writer, contentType string, data []byte) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qw := quotedprintable.NewWriter(part)
+	if _, err := qw.Write(data); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// writeBase64Part writes a base64-encoded part, folding the encoded body
+// at 76 octets per RFC 2045. extraHeaders, if non-nil, are merged in
+// alongside Content-Type and Content-Transfer-Encoding.
+func writeBase64Part(mw *multipart.Writer, contentType string, extraHeaders textproto.MIMEHeader, data []byte) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	for k, v := range extraHeaders {
+		header[k] = v
+	}
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > base64LineLength {
+		if _, err := part.Write([]byte(encoded[:base64LineLength] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+// writeAttachmentPart renders a single Attachment as a MIME part: content
+// type sniffed with http.DetectContentType (TNEF rarely carries an
+// explicit MIME type for attachments), an RFC 2231/6266-encoded
+// Content-Disposition filename, and a Content-ID when the attachment was
+// flagged as inline.
+func writeAttachmentPart(mw *multipart.Writer, a *Attachment) error {
+	contentType := "application/octet-stream"
+	if len(a.Data) > 0 {
+		contentType = http.DetectContentType(a.Data)
+	}
+
+	title := sanitizeHeaderValue(a.Title)
+	if title == "" {
+		title = "attachment.bin"
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": title})
+
+	header := textproto.MIMEHeader{
+		"Content-Disposition": {disposition},
+	}
+	if cid := sanitizeHeaderValue(a.ContentID); cid != "" {
+		header.Set("Content-ID", messageID(cid))
+	}
+
+	return writeBase64Part(mw, contentType, header, a.Data)
+}
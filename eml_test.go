@@ -0,0 +1,85 @@
+package tnef
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestEML(t *testing.T) {
+	tests := []struct {
+		name    string
+		tnef    *Data
+		header  string
+		want    string
+		wantIn  []string
+		notIn   []string
+		bodyish bool
+	}{
+		{
+			name: "plain text body with recipients",
+			tnef: &Data{
+				Subject: "Hello",
+				From:    "alice@example.com",
+				Body:    []byte("hi there"),
+				Recipients: []Recipient{
+					{DisplayName: "Bob", EmailAddress: "bob@example.com", RecipType: 1},
+					{DisplayName: "Carol", EmailAddress: "carol@example.com", RecipType: 2},
+				},
+			},
+			header: "To",
+			want:   "Bob <bob@example.com>",
+		},
+		{
+			name: "header injection is sanitized",
+			tnef: &Data{
+				Subject: "Hello",
+				From:    "alice@example.com",
+				Body:    []byte("hi there"),
+				Attachments: []*Attachment{
+					{Title: "evil\r\nX-Injected: yes", ContentID: "cid\r\nX-Injected: yes", Data: []byte("data")},
+				},
+			},
+			notIn: []string{"\r\nX-Injected", "\nX-Injected"},
+		},
+		{
+			name: "no body and no attachments still produces a valid part",
+			tnef: &Data{
+				Subject: "Empty",
+				From:    "alice@example.com",
+			},
+			wantIn: []string{"multipart/mixed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.tnef.EML()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(string(out)))
+			if err != nil {
+				t.Fatalf("output is not a valid RFC 5322 message: %v\n%s", err, out)
+			}
+
+			if tt.header != "" {
+				got := msg.Header.Get(tt.header)
+				if got != tt.want {
+					t.Fatalf("header %s = %q, want %q", tt.header, got, tt.want)
+				}
+			}
+			for _, s := range tt.wantIn {
+				if !strings.Contains(string(out), s) {
+					t.Fatalf("expected output to contain %q, got:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.notIn {
+				if strings.Contains(string(out), s) {
+					t.Fatalf("expected output not to contain %q, got:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package tnef
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// icalDateTime renders t in the iCalendar UTC basic format (with a trailing
+// "Z") that DTSTAMP/DTSTART/DTEND values use.
+func icalDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icalEscape escapes the characters RFC 5545 3.3.11 requires escaped in
+// TEXT values.
+func icalEscape(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`;`, `\;`,
+		`,`, `\,`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// icalFold wraps a content line at 75 octets as required by RFC 5545 3.1,
+// continuing folded lines with a single leading space.
+func icalFold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var out bytes.Buffer
+	n := limit
+	for len(line) > n {
+		out.WriteString(line[:n])
+		out.WriteString("\r\n ")
+		line = line[n:]
+		// Continuation lines spend one octet on the leading space.
+		n = limit - 1
+	}
+	out.WriteString(line)
+	return out.String()
+}
+
+// decodeRecurrencePattern derives a best-effort RRULE from a
+// PidLidAppointmentRecur blob. MS-OXOCAL's RecurrencePattern structure has
+// many pattern-type-specific fields (day-of-week bitmasks, exception
+// lists, end dates); this reads only the common header (frequency and
+// period) that's enough for a FREQ/INTERVAL rule, which covers the
+// ordinary non-exotic recurrences TNEF messages carry in practice.
+func decodeRecurrencePattern(data []byte) (string, bool) {
+	if len(data) < 16 {
+		return "", false
+	}
+
+	recurFrequency := binary.LittleEndian.Uint16(data[2:4])
+	period := binary.LittleEndian.Uint32(data[12:16])
+
+	var freq string
+	var interval uint32
+	switch recurFrequency {
+	case 0x200a: // daily; period is expressed in minutes per occurrence
+		freq = "DAILY"
+		interval = period / 1440
+	case 0x200b: // weekly; period is in weeks
+		freq = "WEEKLY"
+		interval = period
+	case 0x200c: // monthly; period is in months
+		freq = "MONTHLY"
+		interval = period
+	case 0x200d: // yearly; period is in months
+		freq = "YEARLY"
+		interval = period / 12
+	default:
+		return "", false
+	}
+
+	if interval == 0 {
+		interval = 1
+	}
+
+	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, interval), true
+}
+
+// dtstamp returns the timestamp to render as DTSTAMP, which RFC 5545
+// requires on every VEVENT/VTODO: DateSent if the message carried one
+// (when this is set is the date the invite was actually created), or the
+// current time otherwise so the component is never emitted without one.
+func dtstamp(tnef *Data) time.Time {
+	if !tnef.DateSent.IsZero() {
+		return tnef.DateSent
+	}
+	return time.Now()
+}
+
+// ICalendar renders the appointment carried by this Data as an RFC 5545
+// iCalendar document: a VEVENT for ordinary meeting requests, or a VTODO
+// when MessageClass identifies a task item. It returns an error if no
+// Appointment was decoded.
+func (tnef *Data) ICalendar() ([]byte, error) {
+	if tnef.Appointment == nil {
+		return nil, errors.New("tnef: no appointment data to render")
+	}
+	appt := tnef.Appointment
+
+	component := "VEVENT"
+	if strings.Contains(strings.ToLower(tnef.MessageClass), "task") {
+		component = "VTODO"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("BEGIN:VCALENDAR\r\n")
+	buf.WriteString("VERSION:2.0\r\n")
+	buf.WriteString("PRODID:-//teamwork/tnef//ICalendar//EN\r\n")
+	buf.WriteString("METHOD:REQUEST\r\n")
+	fmt.Fprintf(&buf, "BEGIN:%s\r\n", component)
+
+	if appt.UID != "" {
+		fmt.Fprintf(&buf, "UID:%s\r\n", icalEscape(appt.UID))
+	}
+	fmt.Fprintf(&buf, "DTSTAMP:%s\r\n", icalDateTime(dtstamp(tnef)))
+	if !appt.Start.IsZero() {
+		fmt.Fprintf(&buf, "DTSTART:%s\r\n", icalDateTime(appt.Start))
+	}
+	if !appt.End.IsZero() {
+		tag := "DTEND"
+		if component == "VTODO" {
+			tag = "DUE"
+		}
+		fmt.Fprintf(&buf, "%s:%s\r\n", tag, icalDateTime(appt.End))
+	}
+	if tnef.Subject != "" {
+		buf.WriteString(icalFold(fmt.Sprintf("SUMMARY:%s", icalEscape(tnef.Subject))))
+		buf.WriteString("\r\n")
+	}
+	if appt.Location != "" {
+		buf.WriteString(icalFold(fmt.Sprintf("LOCATION:%s", icalEscape(appt.Location))))
+		buf.WriteString("\r\n")
+	}
+	if tnef.From != "" {
+		fmt.Fprintf(&buf, "ORGANIZER;CN=%s:mailto:%s\r\n", icalEscape(tnef.From), icalEscape(tnef.From))
+	}
+	for _, r := range tnef.Recipients {
+		addr := r.EmailAddress
+		if addr == "" {
+			addr = r.DisplayName
+		}
+		if addr == "" {
+			continue
+		}
+		buf.WriteString(icalFold(fmt.Sprintf("ATTENDEE;CN=%s:mailto:%s", icalEscape(r.DisplayName), icalEscape(addr))))
+		buf.WriteString("\r\n")
+	}
+	if len(appt.RecurrencePattern) > 0 {
+		if rrule, ok := decodeRecurrencePattern(appt.RecurrencePattern); ok {
+			fmt.Fprintf(&buf, "RRULE:%s\r\n", rrule)
+		}
+	}
+
+	fmt.Fprintf(&buf, "END:%s\r\n", component)
+	buf.WriteString("END:VCALENDAR\r\n")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package tnef
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalendar(t *testing.T) {
+	t.Run("no appointment", func(t *testing.T) {
+		if _, err := (&Data{}).ICalendar(); err == nil {
+			t.Fatal("expected an error when no appointment was decoded")
+		}
+	})
+
+	t.Run("VEVENT with DTSTAMP, dates and RRULE", func(t *testing.T) {
+		start := time.Date(2026, 3, 5, 14, 0, 0, 0, time.UTC)
+		end := time.Date(2026, 3, 5, 15, 0, 0, 0, time.UTC)
+		sent := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+		tnef := &Data{
+			Subject:  "Status sync",
+			From:     "alice@example.com",
+			DateSent: sent,
+			Appointment: &Appointment{
+				UID:   "abc123",
+				Start: start,
+				End:   end,
+				// FREQ=WEEKLY header: recurFrequency=0x200b, period=1 week.
+				RecurrencePattern: []byte{
+					0, 0, 0x0b, 0x20, 0, 0, 0, 0,
+					0, 0, 0, 0, 1, 0, 0, 0,
+				},
+			},
+		}
+
+		out, err := tnef.ICalendar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ical := string(out)
+
+		if !strings.Contains(ical, "BEGIN:VEVENT") {
+			t.Fatal("expected a VEVENT component")
+		}
+		if !strings.Contains(ical, "DTSTAMP:"+icalDateTime(sent)) {
+			t.Fatalf("expected DTSTAMP to use DateSent, got:\n%s", ical)
+		}
+		if !strings.Contains(ical, "DTSTART:"+icalDateTime(start)) {
+			t.Fatalf("expected DTSTART, got:\n%s", ical)
+		}
+		if !strings.Contains(ical, "DTEND:"+icalDateTime(end)) {
+			t.Fatalf("expected DTEND, got:\n%s", ical)
+		}
+		if !strings.Contains(ical, "RRULE:FREQ=WEEKLY;INTERVAL=1") {
+			t.Fatalf("expected an RRULE, got:\n%s", ical)
+		}
+	})
+
+	t.Run("VTODO uses DUE and DTSTAMP falls back to now", func(t *testing.T) {
+		due := time.Date(2026, 4, 1, 17, 0, 0, 0, time.UTC)
+		tnef := &Data{
+			MessageClass: "IPM.Task",
+			Appointment: &Appointment{
+				End: due,
+			},
+		}
+
+		out, err := tnef.ICalendar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ical := string(out)
+
+		if !strings.Contains(ical, "BEGIN:VTODO") {
+			t.Fatalf("expected a VTODO component, got:\n%s", ical)
+		}
+		if !strings.Contains(ical, "DUE:"+icalDateTime(due)) {
+			t.Fatalf("expected DUE, got:\n%s", ical)
+		}
+		if !strings.Contains(ical, "DTSTAMP:") {
+			t.Fatalf("expected a DTSTAMP even with no DateSent, got:\n%s", ical)
+		}
+	})
+}
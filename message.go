@@ -0,0 +1,108 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// addAttr folds a message-level TNEF object (one with Level != lvlAttachment)
+// into tnef, mirroring Attachment.addAttr for the per-attachment attributes.
+func (tnef *Data) addAttr(obj *TNEFObject) error {
+	switch obj.Name {
+	case ATTBODY:
+		tnef.Body = obj.Data
+	case ATTSUBJECT:
+		tnef.Subject = cleanString(obj.Data)
+	case ATTFROM:
+		tnef.From = cleanString(obj.Data)
+	case ATTMESSAGECLASS:
+		tnef.MessageClass = cleanString(obj.Data)
+	case ATTMESSAGEID:
+		tnef.MessageID = cleanString(obj.Data)
+	case ATTPRIORITY:
+		tnef.Priority = obj.Data
+	case ATTDATESENT:
+		if t, err := decodeDTR(obj.Data); err == nil {
+			tnef.DateSent = t
+		}
+	case ATTDATESTART:
+		if t, err := decodeDTR(obj.Data); err == nil {
+			tnef.ensureAppointment().Start = t
+		}
+	case ATTDATEEND:
+		if t, err := decodeDTR(obj.Data); err == nil {
+			tnef.ensureAppointment().End = t
+		}
+	case ATTAIDOWNER:
+		tnef.ensureAppointment().OwnerApptID = obj.Data
+	case ATTREQUESTRES:
+		tnef.ensureAppointment().ResponseRequested = len(obj.Data) > 0 && obj.Data[0] != 0
+	case ATTRECIPTABLE:
+		recipients, err := decodeRecipientTable(obj.Data)
+		if err != nil {
+			return err
+		}
+		tnef.Recipients = recipients
+	case ATTMAPIPROPS:
+		attributes, _, err := decodeMapi(obj.Data, 0)
+		if err != nil {
+			return err
+		}
+		tnef.Attributes = append(tnef.Attributes, attributes...)
+
+		for _, attr := range attributes {
+			switch attr.Name {
+			case MAPIBody:
+				tnef.Body = attr.Data
+			case MAPIBodyHTML:
+				tnef.BodyHTML = attr.Data
+			case MAPISenderName:
+				if tnef.From == "" {
+					tnef.From = cleanString(attr.Data)
+				}
+			case MAPIImportance:
+				if tnef.Priority == nil {
+					tnef.Priority = attr.Data
+				}
+			case MAPIClientSubmitTime:
+				if tnef.DateSent.IsZero() {
+					if t, err := filetimeToTime(attr.Data); err == nil {
+						tnef.DateSent = t
+					}
+				}
+			case MAPIRTFCompressed:
+				rtf, err := DecompressRTF(attr.Data)
+				if err == nil {
+					tnef.RTFBody = rtf
+				}
+			case MAPILocation:
+				tnef.ensureAppointment().Location = cleanString(attr.Data)
+			case MAPIRecurrencePattern:
+				tnef.ensureAppointment().RecurrencePattern = attr.Data
+			case MAPIGlobalObjectID:
+				tnef.ensureAppointment().UID = fmt.Sprintf("%x", attr.Data)
+			case MAPIResponseStatus:
+				if len(attr.Data) >= 4 {
+					tnef.ensureAppointment().ResponseStatus = binary.LittleEndian.Uint32(attr.Data)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// cleanString removes every NUL byte from data, which drops the trailing
+// NUL terminator TNEF string attributes carry on the wire.
+func cleanString(data []byte) string {
+	return strings.Replace(string(data), "\x00", "", -1)
+}
+
+// ensureAppointment returns tnef.Appointment, allocating it on first use.
+func (tnef *Data) ensureAppointment() *Appointment {
+	if tnef.Appointment == nil {
+		tnef.Appointment = &Appointment{}
+	}
+	return tnef.Appointment
+}
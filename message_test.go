@@ -0,0 +1,95 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// buildFiletime encodes t as a Windows FILETIME, the inverse of
+// filetimeToTime.
+func buildFiletime(t time.Time) string {
+	units := uint64(t.UnixNano()/100) + ftEpochDiff
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, units)
+	return string(buf)
+}
+
+// buildMapiRow builds a single MAPI property stream carrying the given
+// properties, encoding each value the way decodeMapiValue expects for its
+// tag's property type: fixed-size types (ptLong, ptSysTime, ...) as plain
+// bytes, everything else length-prefixed and padded to a 4-byte boundary.
+func buildMapiRow(props map[uint32]string) []byte {
+	var data []byte
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(len(props)))
+	data = append(data, count...)
+
+	for tag, value := range props {
+		propType := uint16(tag >> 16)
+		propID := uint16(tag)
+		tagBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint16(tagBytes, propType)
+		binary.LittleEndian.PutUint16(tagBytes[2:], propID)
+		data = append(data, tagBytes...)
+
+		switch propType {
+		case ptShort, ptLong, ptFloat, ptError, ptBoolean, ptDouble, ptCurrency, ptAppTime, ptI8, ptSysTime, ptClsid:
+			data = append(data, value...)
+		default:
+			length := make([]byte, 4)
+			binary.LittleEndian.PutUint32(length, uint32(len(value)))
+			data = append(data, length...)
+			data = append(data, value...)
+			if pad := (4 + len(value)) % 4; pad != 0 {
+				data = append(data, make([]byte, 4-pad)...)
+			}
+		}
+	}
+
+	return data
+}
+
+func TestAddAttrMapiFallbacks(t *testing.T) {
+	sent := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+	importance := make([]byte, 4)
+	binary.LittleEndian.PutUint32(importance, 2)
+
+	stream := buildTNEFStream(seedTNEFObject(lvlMessage, ATTMAPIPROPS, buildMapiRow(map[uint32]string{
+		MAPISenderName:       "Alice",
+		MAPIImportance:       string(importance),
+		MAPIClientSubmitTime: buildFiletime(sent),
+	})))
+
+	tnef, err := Decode(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tnef.From != "Alice" {
+		t.Fatalf("got From %q, want %q", tnef.From, "Alice")
+	}
+	if !tnef.DateSent.Equal(sent) {
+		t.Fatalf("got DateSent %v, want %v", tnef.DateSent, sent)
+	}
+	if string(tnef.Priority) != string(importance) {
+		t.Fatalf("got Priority %v, want %v", tnef.Priority, importance)
+	}
+}
+
+func TestAddAttrMapiFallbacksDoNotOverrideExplicitAttributes(t *testing.T) {
+	stream := buildTNEFStream(
+		seedTNEFObject(lvlMessage, ATTFROM, []byte("explicit@example.com\x00")),
+		seedTNEFObject(lvlMessage, ATTMAPIPROPS, buildMapiRow(map[uint32]string{
+			MAPISenderName: "Alice",
+		})),
+	)
+
+	tnef, err := Decode(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tnef.From != "explicit@example.com" {
+		t.Fatalf("got From %q, want the explicit ATTFROM value", tnef.From)
+	}
+}
@@ -0,0 +1,77 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Recipient is one entry from a TNEF ATTRECIPTABLE, decoded from its MAPI
+// property stream.
+type Recipient struct {
+	DisplayName  string
+	EmailAddress string
+	AddressType  string
+	RecipType    uint32
+	SearchKey    []byte
+}
+
+// decodeRecipientTable decodes an ATTRECIPTABLE attribute: a uint32 row
+// count followed by that many rows, each itself a MAPI property stream.
+func decodeRecipientTable(data []byte) ([]Recipient, error) {
+	if len(data) < 4 {
+		return nil, errors.New("tnef: truncated recipient table")
+	}
+	rowCount := binary.LittleEndian.Uint32(data)
+
+	offset := 4
+	recipients := make([]Recipient, 0, recipientPreallocCount(data, rowCount))
+
+	for i := uint32(0); i < rowCount; i++ {
+		attributes, n, err := decodeMapi(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset += n
+
+		recipient := Recipient{}
+		for _, attr := range attributes {
+			switch attr.Name {
+			case MAPIDisplayName:
+				recipient.DisplayName = cleanString(attr.Data)
+			case MAPIEmailAddress:
+				recipient.EmailAddress = cleanString(attr.Data)
+			case MAPIAddressType:
+				recipient.AddressType = cleanString(attr.Data)
+			case MAPIRecipientType:
+				if len(attr.Data) >= 4 {
+					recipient.RecipType = binary.LittleEndian.Uint32(attr.Data)
+				}
+			case MAPISearchKey:
+				recipient.SearchKey = attr.Data
+			}
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+// recipientPreallocCount bounds the initial capacity hint used for the
+// decoded recipient slice. rowCount comes straight off the wire as the
+// ATTRECIPTABLE row count and can't be trusted on its own: a handful of
+// bytes can claim billions of rows and make the allocation itself crash
+// the process before a single row is decoded. Cap it against the number of
+// rows the remaining buffer could possibly hold instead; a legitimately
+// larger table still decodes fine via append, it just won't be
+// preallocated in one shot.
+func recipientPreallocCount(data []byte, rowCount uint32) int {
+	// Each row is itself a MAPI property stream, which needs at least 4
+	// bytes for its property count field alone.
+	const minRowSize = 4
+
+	maxRows := uint64(len(data)) / minRowSize
+	if uint64(rowCount) < maxRows {
+		return int(rowCount)
+	}
+	return int(maxRows)
+}
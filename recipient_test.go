@@ -0,0 +1,98 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildMapiString8Row builds a single MAPI property stream carrying the
+// given ptString8 properties, in the form decodeRecipientTable expects for
+// each ATTRECIPTABLE row.
+func buildMapiString8Row(props map[uint32]string) []byte {
+	var data []byte
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(len(props)))
+	data = append(data, count...)
+
+	for tag, value := range props {
+		propType := uint16(tag >> 16)
+		propID := uint16(tag)
+		tagBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint16(tagBytes, propType)
+		binary.LittleEndian.PutUint16(tagBytes[2:], propID)
+		data = append(data, tagBytes...)
+
+		length := make([]byte, 4)
+		binary.LittleEndian.PutUint32(length, uint32(len(value)))
+		data = append(data, length...)
+		data = append(data, value...)
+		if pad := (4 + len(value)) % 4; pad != 0 {
+			data = append(data, make([]byte, 4-pad)...)
+		}
+	}
+
+	return data
+}
+
+func buildRecipientTable(rowCount uint32, rows ...[]byte) []byte {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, rowCount)
+	for _, row := range rows {
+		data = append(data, row...)
+	}
+	return data
+}
+
+func TestDecodeRecipientTable(t *testing.T) {
+	t.Run("multi-row fixture", func(t *testing.T) {
+		row1 := buildMapiString8Row(map[uint32]string{
+			MAPIDisplayName:  "Alice",
+			MAPIEmailAddress: "alice@example.com",
+		})
+		row2 := buildMapiString8Row(map[uint32]string{
+			MAPIDisplayName:  "Bob",
+			MAPIEmailAddress: "bob@example.com",
+		})
+		data := buildRecipientTable(2, row1, row2)
+
+		recipients, err := decodeRecipientTable(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recipients) != 2 {
+			t.Fatalf("got %d recipients, want 2", len(recipients))
+		}
+		if recipients[0].DisplayName != "Alice" || recipients[0].EmailAddress != "alice@example.com" {
+			t.Fatalf("unexpected first recipient: %+v", recipients[0])
+		}
+		if recipients[1].DisplayName != "Bob" || recipients[1].EmailAddress != "bob@example.com" {
+			t.Fatalf("unexpected second recipient: %+v", recipients[1])
+		}
+	})
+
+	t.Run("truncated table", func(t *testing.T) {
+		if _, err := decodeRecipientTable([]byte{1, 2}); err == nil {
+			t.Fatal("expected an error for a truncated table")
+		}
+	})
+
+	t.Run("rejects a rowCount wildly out of proportion to the data", func(t *testing.T) {
+		// rowCount claims ~4 billion rows, but there's no row data to back
+		// it: decodeMapi must fail on the first iteration rather than the
+		// preallocation itself crashing the process.
+		data := buildRecipientTable(0xfffffff0)
+
+		if _, err := decodeRecipientTable(data); err == nil {
+			t.Fatal("expected an error for a bogus row count")
+		}
+	})
+}
+
+func TestRecipientPreallocCount(t *testing.T) {
+	if got := recipientPreallocCount(make([]byte, 100), 0xfffffff0); got > 25 {
+		t.Fatalf("got %d, want a count bounded by len(data)/4", got)
+	}
+	if got := recipientPreallocCount(make([]byte, 100), 3); got != 3 {
+		t.Fatalf("got %d, want the smaller rowCount of 3", got)
+	}
+}
@@ -0,0 +1,150 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+const (
+	rtfCompressed   = 0x75465a4c // "LZFu"
+	rtfUncompressed = 0x414c454d // "MELA"
+
+	rtfDictSize = 4096
+)
+
+// rtfPrebuf is the fixed dictionary LZFu-compressed RTF bodies are seeded
+// with, so the first back-references in a stream can point at common RTF
+// boilerplate instead of having to spell it out.
+const rtfPrebuf = "{\\rtf1\\ansi\\mac\\deff0\\deftab720{\\fonttbl;}{\\f0\\fnil \\froman \\fswiss \\fmodern \\fscript \\fdecor MS Sans SerifSymbolArialTimes New RomanCourier{\\colortbl\\red0\\green0\\blue0\n\r\\par \\pard\\plain\\f0\\fs20\\b\\i\\u\\tab\\tx"
+
+// DecompressRTF decodes a PR_RTF_COMPRESSED value into plain RTF. The wire
+// format is a 16-byte header (compressed size, raw size, a magic number
+// identifying the compression used, and a CRC32 of the compressed payload)
+// followed by the payload itself. Two magic numbers are recognized: "LZFu"
+// for LZ77-style compressed data, and "MELA" for payloads that are stored
+// verbatim.
+func DecompressRTF(data []byte) ([]byte, error) {
+	if len(data) < 16 {
+		return nil, errors.New("tnef: truncated compressed rtf header")
+	}
+
+	compSize := binary.LittleEndian.Uint32(data[0:])
+	rawSize := binary.LittleEndian.Uint32(data[4:])
+	magic := binary.LittleEndian.Uint32(data[8:])
+	checksum := binary.LittleEndian.Uint32(data[12:])
+
+	payload := data[16:]
+	// compSize counts the magic and checksum words as part of the
+	// compressed size, so the payload itself is 8 bytes shorter.
+	if compSize < 8 {
+		return nil, errors.New("tnef: invalid compressed rtf size")
+	}
+	payloadSize := int(compSize) - 8
+	if payloadSize > len(payload) {
+		return nil, errors.New("tnef: truncated compressed rtf payload")
+	}
+	payload = payload[:payloadSize]
+
+	switch magic {
+	case rtfUncompressed:
+		if crc32.ChecksumIEEE(payload) != checksum && checksum != 0 {
+			return nil, errors.New("tnef: compressed rtf checksum mismatch")
+		}
+		return append([]byte{}, payload...), nil
+
+	case rtfCompressed:
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return nil, errors.New("tnef: compressed rtf checksum mismatch")
+		}
+		return lzfuDecompress(payload, rawSize)
+
+	default:
+		return nil, errors.New("tnef: unrecognized compressed rtf magic")
+	}
+}
+
+// lzfuDecompress runs the LZFu back-reference stream in payload through a
+// 4096-byte ring dictionary preloaded with rtfPrebuf, producing at most
+// rawSize bytes of plain RTF.
+func lzfuDecompress(payload []byte, rawSize uint32) ([]byte, error) {
+	var dict [rtfDictSize]byte
+	copy(dict[:], rtfPrebuf)
+	writeCur := len(rtfPrebuf)
+
+	out := make([]byte, 0, rtfPreallocSize(payload, rawSize))
+	pos := 0
+
+	for pos < len(payload) && uint32(len(out)) < rawSize {
+		control := payload[pos]
+		pos++
+
+		for bit := 0; bit < 8; bit++ {
+			if uint32(len(out)) >= rawSize {
+				break
+			}
+			if pos >= len(payload) {
+				return nil, errors.New("tnef: truncated lzfu control run")
+			}
+
+			if control&(1<<uint(bit)) == 0 {
+				b := payload[pos]
+				pos++
+
+				out = append(out, b)
+				dict[writeCur] = b
+				writeCur = (writeCur + 1) % rtfDictSize
+				continue
+			}
+
+			if pos+2 > len(payload) {
+				return nil, errors.New("tnef: truncated lzfu token")
+			}
+			token := binary.BigEndian.Uint16(payload[pos:])
+			pos += 2
+
+			dictOffset := int(token >> 4)
+			length := int(token&0xf) + 2
+
+			if dictOffset == writeCur {
+				// End-of-stream marker.
+				return out, nil
+			}
+
+			for i := 0; i < length && uint32(len(out)) < rawSize; i++ {
+				b := dict[dictOffset]
+				dictOffset = (dictOffset + 1) % rtfDictSize
+
+				out = append(out, b)
+				dict[writeCur] = b
+				writeCur = (writeCur + 1) % rtfDictSize
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// rtfPreallocSize bounds the initial capacity hint used for the
+// decompressed output. rawSize comes straight off the wire inside
+// PR_RTF_COMPRESSED and can't be trusted on its own: a handful of bytes
+// can claim a multi-gigabyte rawSize and make the allocation itself crash
+// the process before a single byte is decompressed. Cap it against a
+// generous multiple of the actual compressed payload length instead; a
+// legitimately larger output still grows fine via append, it just won't
+// be preallocated in one shot.
+func rtfPreallocSize(payload []byte, rawSize uint32) int {
+	const (
+		maxExpansion   = 16      // a single 2-byte LZFu token can emit up to 17 bytes, i.e. 8.5 per payload byte
+		maxPreallocCap = 1 << 26 // never preallocate more than 64MiB up front
+	)
+
+	bound := uint64(len(payload))*maxExpansion + 1024
+	if bound > maxPreallocCap {
+		bound = maxPreallocCap
+	}
+	if uint64(rawSize) < bound {
+		return int(rawSize)
+	}
+	return int(bound)
+}
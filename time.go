@@ -0,0 +1,48 @@
+package tnef
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// ftEpochDiff is the number of 100-nanosecond intervals between the Windows
+// FILETIME epoch (1601-01-01) and the Unix epoch (1970-01-01).
+const ftEpochDiff = 116444736000000000
+
+// decodeDTR decodes a TNEF "date triple" as used by attributes such as
+// ATTDATESTART, ATTDATEEND and ATTDATESENT: seven little-endian uint16
+// fields, year/month/day/hour/minute/second/dow, in that order.
+func decodeDTR(data []byte) (time.Time, error) {
+	if len(data) < 14 {
+		return time.Time{}, errors.New("tnef: truncated date attribute")
+	}
+
+	year := binary.LittleEndian.Uint16(data[0:])
+	month := binary.LittleEndian.Uint16(data[2:])
+	day := binary.LittleEndian.Uint16(data[4:])
+	hour := binary.LittleEndian.Uint16(data[6:])
+	minute := binary.LittleEndian.Uint16(data[8:])
+	second := binary.LittleEndian.Uint16(data[10:])
+
+	return time.Date(
+		int(year), time.Month(month), int(day),
+		int(hour), int(minute), int(second), 0, time.UTC,
+	), nil
+}
+
+// filetimeToTime converts a Windows FILETIME (100-ns intervals since
+// 1601-01-01, as used by MAPI's ptSysTime properties) to a time.Time.
+func filetimeToTime(data []byte) (time.Time, error) {
+	if len(data) < 8 {
+		return time.Time{}, errors.New("tnef: truncated filetime value")
+	}
+
+	ft := binary.LittleEndian.Uint64(data[:8])
+	if ft < ftEpochDiff {
+		return time.Time{}, errors.New("tnef: filetime predates the Unix epoch")
+	}
+
+	units := int64(ft - ftEpochDiff)
+	return time.Unix(0, units*100).UTC(), nil
+}
@@ -2,15 +2,16 @@
 package tnef // import "github.com/teamwork/tnef"
 
 import (
-	"encoding/binary"
+	"bytes"
 	"errors"
 	"io/ioutil"
 	"strings"
+	"time"
 )
 
 const (
 	tnefSignature = 0x223e9f78
-	//lvlMessage    = 0x01
+	lvlMessage    = 0x01
 	lvlAttachment = 0x02
 
 	MIN_OBJ_SIZE = 12
@@ -68,6 +69,20 @@ type Attachment struct {
 	Data             []byte
 	ModificationDate []byte
 	CreationDate     []byte
+
+	// Embedded holds the decoded contents of this attachment when it is
+	// itself a nested TNEF stream, e.g. a forwarded message/rfc822.
+	Embedded *Data
+
+	// EmbeddedMessage holds the raw compound-document (IMessage/CFBF,
+	// i.e. .msg) bytes when this attachment is an embedded message that
+	// isn't TNEF-encoded. Callers can walk it with their own CFBF reader.
+	EmbeddedMessage []byte
+
+	// ContentID is set when the sender flagged this attachment as an
+	// inline part of the body (PR_ATTACH_CONTENT_ID), e.g. an image
+	// referenced from BodyHTML.
+	ContentID string
 }
 
 // ErrNoMarker signals that the file did not start with the fixed TNEF marker,
@@ -77,15 +92,26 @@ var ErrNoMarker = errors.New("Wrong TNEF signature")
 
 // Data contains the various data from the extracted TNEF file.
 type Data struct {
-	Body        []byte
-	BodyHTML    []byte
-	Attachments []*Attachment
-	Attributes  []MAPIAttribute
-	RTFBody     []byte
-	key         uint16
+	Body         []byte
+	BodyHTML     []byte
+	RTFBody      []byte
+	Subject      string
+	From         string
+	MessageClass string
+	MessageID    string
+	DateSent     time.Time
+	Priority     []byte
+	Attachments  []*Attachment
+	Attributes   []MAPIAttribute
+	Recipients   []Recipient
+	Appointment  *Appointment
+	key          uint16
 }
 
-func (a *Attachment) addAttr(obj *TNEFObject) {
+// addAttr folds an attachment-level TNEF object into a. opts, budget and
+// depth bound recursive decoding of a MAPIAttachDataObj that is itself a
+// TNEF stream; see decodeEmbedded.
+func (a *Attachment) addAttr(obj *TNEFObject, opts DecoderOptions, budget *embedBudget, depth int) error {
 
 	switch obj.Name {
 	case ATTATTACHMODIFYDATE:
@@ -97,24 +123,29 @@ func (a *Attachment) addAttr(obj *TNEFObject) {
 	case ATTATTACHDATA:
 		a.Data = obj.Data
 	case ATTATTACHMENT:
-		attributes, err := decodeMapi(obj.Data, 0)
+		attributes, _, err := decodeMapi(obj.Data, 0)
 		if err == nil {
 			for _, attr := range attributes {
 				switch attr.Name {
 				case MAPIAttachFilename, MAPIDisplayName:
 					a.Title = strings.Replace(string(attr.Data), "\x00", "", -1)
-					// case MAPIAttachDataObj:
-					// 	if bytes.HasPrefix(obj.Data, IMessageSig) {
-					// 		a.Data = obj.Data[IMessageSigLen:]
-					// 		a.Embed, _ = Decode(obj.Data)
-					// 	} else {
-					// 		a.Data = obj.Data
-					// 	}
-					// }
+				case MAPIAttachContentID:
+					a.ContentID = strings.Replace(string(attr.Data), "\x00", "", -1)
+				case MAPIAttachDataObj:
+					if isTNEF, isMessage := detectEmbeddedSignature(attr.Data); isTNEF {
+						embedded, err := decodeEmbedded(attr.Data, opts, budget, depth)
+						if err != nil {
+							return err
+						}
+						a.Embedded = embedded
+					} else if isMessage {
+						a.EmbeddedMessage = attr.Data
+					}
 				}
 			}
 		}
 	}
+	return nil
 }
 
 // DecodeFile is a utility function that reads the file into memory
@@ -129,51 +160,8 @@ func DecodeFile(path string) (*Data, error) {
 }
 
 // Decode will accept a stream of bytes in the TNEF format and extract the
-// attachments and body into a Data object.
+// attachments and body into a Data object. It's a thin wrapper around
+// Decoder, for callers who already have the whole blob in memory.
 func Decode(data []byte) (*Data, error) {
-
-	signature := binary.LittleEndian.Uint32(data)
-	if signature != tnefSignature {
-		return nil, ErrNoMarker
-	}
-	tnef := &Data{
-		Attachments: []*Attachment{},
-	}
-	tnef.key = binary.LittleEndian.Uint16(data[4:])
-
-	offset := 6
-	var attachment *Attachment
-
-	for offset+MIN_OBJ_SIZE < len(data) {
-		obj := decodeTNEFObject(data[offset:])
-		offset += int(obj.Length)
-
-		if obj.Name == ATTATTACHRENDDATA {
-			attachment = &Attachment{}
-			tnef.Attachments = append(tnef.Attachments, attachment)
-		} else if obj.Level == lvlAttachment {
-			if attachment != nil {
-				attachment.addAttr(obj)
-			}
-		}
-	}
-
-	return tnef, nil
-}
-
-func decodeTNEFObject(data []byte) (object *TNEFObject) {
-	object = &TNEFObject{}
-	object.Length = uint32(len(data))
-	object.Level = uint8(data[0])
-	object.Name = binary.LittleEndian.Uint16(data[1:])
-	object.Type = binary.BigEndian.Uint16(data[3:])
-
-	length := binary.LittleEndian.Uint32(data[5:]) + 11
-	if length < object.Length {
-		object.Length = length
-	}
-
-	object.Data = data[9 : object.Length-2]
-
-	return
+	return NewDecoder(bytes.NewReader(data)).Decode()
 }